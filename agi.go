@@ -2,14 +2,15 @@ package agi
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
-	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -58,13 +59,27 @@ type AGI struct {
 	// of the AGI session.
 	Variables map[string]string
 
-	r    io.Reader
 	eagi io.Reader
-	w    io.Writer
 
 	conn net.Conn
 
-	mu sync.Mutex
+	// ctx is the session-scoped context returned by Context(); it is
+	// context.Background() unless the session was created by Server
+	// (via BaseContext) or NewConnContext.
+	ctx context.Context
+
+	// idleReset, when set by Server, is invoked after every completed
+	// dispatch to push out the connection's idle deadline.
+	idleReset func()
+
+	// channel owns command dispatch: encoding, response decoding, the
+	// dispatch mutex, and context-cancellation plumbing.
+	channel *Channel
+
+	// metrics and tracer receive command and session instrumentation;
+	// both default to no-op implementations, see SetMetrics/SetTracer.
+	metrics Metrics
+	tracer  Tracer
 
 	// Logging ability
 	logger *zap.Logger
@@ -78,6 +93,7 @@ type Response struct {
 	Result       int    // Result is the numerical return (if parseable)
 	ResultString string // Result value as a string
 	Value        string // Value is the (optional) string value returned
+	RawLine      string // RawLine is the raw response line received from Asterisk, if any
 }
 
 // Res returns the ResultString of a Response, as well as any error encountered.  Depending on the command, this is sometimes more useful than Val()
@@ -95,11 +111,6 @@ func (r *Response) Val() (string, error) {
 	return r.Value, r.Error
 }
 
-// Regex for AGI response result code and value
-var responseRegex = regexp.MustCompile(`^(\d{3})\sresult=(-?[[:alnum:]]*)(\s.*)?$`)
-var responseRegexNoParse = regexp.MustCompile(`^(\d{3})\sresult=(-?[[:alnum:]_*]*)(\s.*)?$`)
-var responseRegexNoParseOtherResponse = regexp.MustCompile(`^(\d{3})\s([\s\w]+)$`)
-
 const (
 	// StatusOK indicates the AGI command was accepted.
 	StatusOK = 200
@@ -126,15 +137,24 @@ func New(r io.Reader, w io.Writer) *AGI {
 // EAGI `io.Reader`, and `os.Stdout` `io.Writer`. The initial variables will
 // be read in.
 func NewWithEAGI(r io.Reader, w io.Writer, eagi io.Reader) *AGI {
+	return newAGI(context.Background(), r, w, eagi, nil)
+}
+
+// newAGI is the common constructor underlying New, NewWithEAGI and
+// NewConn: it reads the initial variables off r and wires up a Channel
+// (bound to conn, if given) using the default AsteriskCodec.
+func newAGI(ctx context.Context, r io.Reader, w io.Writer, eagi io.Reader, conn net.Conn) *AGI {
 	a := AGI{
 		Variables: make(map[string]string),
-		r:         r,
-		w:         w,
 		eagi:      eagi,
+		conn:      conn,
+		ctx:       ctx,
+		metrics:   noopMetrics{},
+		tracer:    noopTracer{},
 		logger:    zap.New(zapcore.NewNopCore()),
 	}
 
-	s := bufio.NewScanner(a.r)
+	s := bufio.NewScanner(r)
 	for s.Scan() {
 		if s.Text() == "" {
 			break
@@ -146,14 +166,23 @@ func NewWithEAGI(r io.Reader, w io.Writer, eagi io.Reader) *AGI {
 		}
 	}
 
+	a.channel = NewChannel(r, w, conn, AsteriskCodec{})
+	a.channel.onDispatch = a.handleDispatch
+
 	return &a
 }
 
 // NewConn returns a new AGI session bound to the given net.Conn interface
 func NewConn(conn net.Conn) *AGI {
-	a := New(conn, conn)
-	a.conn = conn
-	return a
+	return NewConnContext(context.Background(), conn)
+}
+
+// NewConnContext returns a new AGI session bound to the given net.Conn
+// interface, with ctx available to the handler via AGI.Context(). Server
+// uses this to hand each session the context.Context derived from its
+// BaseContext.
+func NewConnContext(ctx context.Context, conn net.Conn) *AGI {
+	return newAGI(ctx, conn, conn, nil, conn)
 }
 
 // NewStdio returns a new AGI session to stdin and stdout.
@@ -166,28 +195,13 @@ func NewEAGI() *AGI {
 	return NewWithEAGI(os.Stdin, os.Stdout, os.NewFile(uintptr(3), "/dev/stdeagi"))
 }
 
-// Listen binds an AGI HandlerFunc to the given TCP `host:port` address, creating a FastAGI service.
+// Listen binds an AGI HandlerFunc to the given TCP `host:port` address,
+// creating a FastAGI service. It is a thin wrapper around Server, kept for
+// backward compatibility; callers who need bounded concurrency, timeouts,
+// TLS (AGIS), or graceful shutdown should use Server directly.
 func Listen(addr string, handler HandlerFunc) error {
-	if addr == "" {
-		addr = "localhost:4573"
-	}
-
-	l, err := net.Listen("tcp", addr)
-	if err != nil {
-		return fmt.Errorf("failed to bind server: %w", err)
-	}
-	defer func(l net.Listener) {
-		_ = l.Close()
-	}(l)
-
-	for {
-		conn, err := l.Accept()
-		if err != nil {
-			return fmt.Errorf("failed to accept TCP connection: %w", err)
-		}
-
-		go handler(NewConn(conn))
-	}
+	srv := &Server{Addr: addr, Handler: handler}
+	return srv.ListenAndServe()
 }
 
 // Close closes any network connection associated with the AGI instance
@@ -204,200 +218,135 @@ func (a *AGI) EAGI() io.Reader {
 	return a.eagi
 }
 
-// Command sends the given command line to stdout
-// and returns the response.
-// TODO: this does not handle multi-line responses properly
-func (a *AGI) Command(cmd ...string) (resp *Response) {
-	resp = &Response{}
-	cmdString := strings.Join(cmd, " ")
-	var raw string
-
-	a.mu.Lock()
-	defer a.mu.Unlock()
-
-	// Logging raw command and answer
-	if a.logger != nil {
-		defer func() {
-			resString := ""
-			if resp.Error == nil {
-				resString += " Sta:" + strconv.Itoa(resp.Status)
-				resString += " Res:" + strconv.Itoa(resp.Result)
-				if resp.ResultString != "" {
-					resString += " Str:" + resp.ResultString
-				}
-				if resp.Value != "" {
-					resString += " Val:" + resp.Value
-				}
-			} else {
-				resString += " Err:" + resp.Error.Error()
-			}
-			resString = "{" + strings.TrimSpace(resString) + "}"
-			a.logger.Debug(fmt.Sprintf("#%s -> %s -> %s", cmdString, raw, resString))
-		}()
+// Context returns the context associated with this session: for a session
+// handed to a Server.Handler, this is the context.Context derived from
+// Server.BaseContext (context.Background() by default); for a session
+// created by NewConnContext, the ctx passed to it; for every other
+// constructor it is always context.Background().
+func (a *AGI) Context() context.Context {
+	if a.ctx == nil {
+		return context.Background()
 	}
+	return a.ctx
+}
 
-	_, err := a.w.Write([]byte(cmdString + "\n"))
-	if err != nil {
-		resp.Error = fmt.Errorf("failed to send command: %w", err)
-		return resp
-	}
-
-	s := bufio.NewScanner(a.r)
-	for s.Scan() {
-		raw = s.Text()
-		if raw == "" {
-			break
-		}
-
-		if strings.HasPrefix(raw, "HANGUP") {
-			resp.Error = ErrHangup
-			return resp
-		}
-
-		// Parse and store the result code
-		pieces := responseRegex.FindStringSubmatch(raw)
-		if pieces == nil {
-			resp.Error = fmt.Errorf("failed to parse result: %s", raw)
-			return resp
-		}
-
-		// Status code is the first substring
-		resp.Status, err = strconv.Atoi(pieces[1])
-		if err != nil {
-			resp.Error = fmt.Errorf("failed to get status code: %w", err)
-			return resp
-		}
-
-		// Result code is the second substring
-		resp.ResultString = pieces[2]
-		resp.Result, err = strconv.Atoi(pieces[2])
-		if err != nil {
-			resp.Error = fmt.Errorf("failed to parse result-code as an integer: %w", err)
-		}
-
-		// Value is the third (and optional) substring
-		wrappedVal := strings.TrimSpace(pieces[3])
-		resp.Value = strings.TrimSuffix(strings.TrimPrefix(wrappedVal, "("), ")")
+// SetCodec overrides the Codec this session uses to frame commands and
+// decode responses in place of the default AsteriskCodec. It must be
+// called before the first Command/CommandContext call, since the
+// underlying Channel starts reading on first dispatch.
+func (a *AGI) SetCodec(codec Codec) {
+	a.channel.codec = codec
+}
 
-		// FIXME: handle multiple line return values
-		break // nolint
+// SetMetrics overrides the Metrics instrumentation sink used by command
+// dispatch in place of the default no-op implementation.
+func (a *AGI) SetMetrics(m Metrics) {
+	if m == nil {
+		m = noopMetrics{}
 	}
+	a.metrics = m
+}
 
-	// If the Status code is not 200, return an error
-	if resp.Status != 200 {
-		resp.Error = fmt.Errorf("non-200 status code")
+// SetTracer overrides the Tracer used for per-session and per-command
+// events in place of the default no-op implementation, immediately
+// invoking Tracer.OnStart for this session.
+func (a *AGI) SetTracer(t Tracer) {
+	if t == nil {
+		t = noopTracer{}
 	}
+	a.tracer = t
+	a.tracer.OnStart(a)
+}
 
+// dispatch is the single codepath underlying Command, CommandNoParse, and
+// their Context variants: which one is called only changes how its
+// Response is typically consumed, not how it's obtained. Channel.Dispatch
+// already wraps any failure as an *AGIError carrying cmd's Op/Args before
+// onDispatch runs, so logging, Metrics, Tracer, and the caller here all
+// observe the same final error.
+func (a *AGI) dispatch(ctx context.Context, cmd []string) (resp *Response) {
+	resp, _ = a.channel.Dispatch(ctx, cmd)
 	return resp
 }
 
-// CommandNoParse sends the given command line to stdout
-// and returns the response.
-// TODO: this does not handle multi-line responses properly
-func (a *AGI) CommandNoParse(cmd ...string) (resp *Response) {
-	resp = &Response{}
+// handleDispatch is installed as the Channel's onDispatch hook: it
+// reproduces the "#cmd -> {...}" debug line via logDispatch, pushes out
+// the connection's idle deadline via idleReset (for sessions created by a
+// Server with an IdleTimeout configured), and reports the round trip to
+// the session's Metrics and Tracer.
+func (a *AGI) handleDispatch(cmd []string, resp *Response, dur time.Duration) {
 	cmdString := strings.Join(cmd, " ")
-	var raw string
-
-	a.mu.Lock()
-	defer a.mu.Unlock()
-
-	// Logging raw command and answer
-	if a.logger != nil {
-		defer func() {
-			resString := ""
-			if resp.Error == nil {
-				resString += " Sta:" + strconv.Itoa(resp.Status)
-				resString += " Res:" + strconv.Itoa(resp.Result)
-				if resp.ResultString != "" {
-					resString += " Str:" + resp.ResultString
-				}
-				if resp.Value != "" {
-					resString += " Val:" + resp.Value
-				}
-			} else {
-				resString += " Err:" + resp.Error.Error()
-			}
-			resString = "{" + strings.TrimSpace(resString) + "}"
-			a.logger.Debug(fmt.Sprintf("#%s -> %s -> %s", cmdString, raw, resString))
-		}()
-	}
+	a.logDispatch(cmdString, resp)
 
-	_, err := a.w.Write([]byte(cmdString + "\n"))
-	if err != nil {
-		resp.Error = fmt.Errorf("failed to send command: %w", err)
-		return resp
+	if a.idleReset != nil {
+		a.idleReset()
 	}
 
-	s := bufio.NewScanner(a.r)
-	for s.Scan() {
-		raw = s.Text()
-		if raw == "" {
-			break
-		}
-
-		if strings.HasPrefix(raw, "HANGUP") {
-			resp.Error = ErrHangup
-			return resp
-		}
-
-		// Parse and store the result code
-		pieces := responseRegexNoParse.FindStringSubmatch(raw)
-		if pieces == nil {
-			if responseRegexNoParseOtherResponse.MatchString(raw) {
-				pieces = responseRegexNoParseOtherResponse.FindStringSubmatch(raw)
-			} else {
-				resp.Error = fmt.Errorf("failed to parse result: %s", raw)
-				return resp
-			}
-
-		}
+	op := ""
+	if len(cmd) > 0 {
+		op = cmd[0]
+	}
+	a.metrics.CommandLatency(op, dur)
+	a.metrics.CommandCount(op)
+	if resp.Status != 0 {
+		a.metrics.StatusCount(resp.Status)
+	}
 
-		// Status code is the first substring
-		resp.Status, err = strconv.Atoi(pieces[1])
-		if err != nil {
-			resp.Error = fmt.Errorf("failed to get status code: %w", err)
-			return resp
-		}
+	hungUp := errors.Is(resp.Error, ErrHangup)
+	if hungUp {
+		a.metrics.HangupCount()
+	}
 
-		// Result code is the second substring
-		resp.ResultString = pieces[2]
+	a.tracer.OnCommand(cmd, resp, dur)
+	if hungUp {
+		a.tracer.OnHangup(a)
+	}
+}
 
-		if resp.Status == 511 {
-			if strings.EqualFold(resp.ResultString, "Command Not Permitted on a dead channel or intercept routine") {
-				resp.Error = Err511CommandNotPermitted
-			} else {
-				resp.Error = Err511GenericError
-			}
-			return resp
-		}
+// logDispatch is called by handleDispatch for every round trip,
+// reproducing the "#cmd -> {...}" debug line callers have come to expect.
+func (a *AGI) logDispatch(cmdString string, resp *Response) {
+	if a.logger == nil {
+		return
+	}
 
-		resp.Result, err = strconv.Atoi(pieces[2])
-		if err != nil {
-			resp.Result = 1
+	resString := ""
+	if resp.Error == nil {
+		resString += " Sta:" + strconv.Itoa(resp.Status)
+		resString += " Res:" + strconv.Itoa(resp.Result)
+		if resp.ResultString != "" {
+			resString += " Str:" + resp.ResultString
 		}
-
-		// Value is the third (and optional) substring
-		wrappedVal := strings.TrimSpace(pieces[3])
-		resp.Value = strings.TrimSuffix(strings.TrimPrefix(wrappedVal, "("), ")")
-
-		if resp.Value == "timeout" {
-			resp.Error = ErrTimeout
+		if resp.Value != "" {
+			resString += " Val:" + resp.Value
 		}
+	} else {
+		resString += " Err:" + resp.Error.Error()
+	}
+	resString = "{" + strings.TrimSpace(resString) + "}"
+	a.logger.Debug(fmt.Sprintf("#%s -> %s", cmdString, resString))
+}
 
-		if resp.Status == 200 && resp.Value == "-1" {
-			resp.Error = ErrHangup
-			return resp
-		}
+// CommandContext sends the given command line to stdout and returns the
+// response, honoring ctx for per-request deadlines and cancellation.
+func (a *AGI) CommandContext(ctx context.Context, cmd ...string) (resp *Response) {
+	return a.dispatch(ctx, cmd)
+}
 
-		// FIXME: handle multiple line return values
-		break // nolint
-	}
+// Command sends the given command line to stdout
+// and returns the response.
+func (a *AGI) Command(cmd ...string) (resp *Response) {
+	return a.CommandContext(context.Background(), cmd...)
+}
 
-	// If the Status code is not 200, return an error
-	if resp.Status != 200 {
-		resp.Error = fmt.Errorf("non-200 status code")
-	}
+// CommandNoParseContext sends the given command line to stdout and returns
+// the response, honoring ctx for per-request deadlines and cancellation.
+func (a *AGI) CommandNoParseContext(ctx context.Context, cmd ...string) (resp *Response) {
+	return a.dispatch(ctx, cmd)
+}
 
-	return resp
+// CommandNoParse sends the given command line to stdout
+// and returns the response.
+func (a *AGI) CommandNoParse(cmd ...string) (resp *Response) {
+	return a.CommandNoParseContext(context.Background(), cmd...)
 }
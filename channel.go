@@ -0,0 +1,305 @@
+package agi
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// decodeResult carries a single decoded response (or transport error) from
+// the background reader goroutine to the Dispatch call waiting on it.
+type decodeResult struct {
+	resp *Response
+	err  error
+}
+
+// Channel owns the wire-level mechanics of an AGI session: the
+// reader/writer pair, the Codec used to frame commands and decode
+// responses, the dispatch mutex, and the background goroutine that lets a
+// blocked read be unblocked by context cancellation rather than by the
+// caller.
+//
+// Responses are handed to callers through a FIFO queue of waiters rather
+// than a shared channel: since the AGI protocol is strictly one response
+// per command, the queue is what keeps a response that arrives after its
+// Dispatch call has already given up (a cancelled stdio read that can't
+// actually be interrupted) from being handed to a later, unrelated
+// command.
+//
+// Command and CommandNoParse are both thin wrappers around Channel.Dispatch;
+// the only thing that varies between an AGI session using the default
+// dialect and one under test with a custom protocol is the Codec supplied
+// to NewChannel.
+type Channel struct {
+	r    *bufio.Reader
+	w    io.Writer
+	conn net.Conn
+
+	codec Codec
+
+	mu sync.Mutex
+
+	readerOnce sync.Once
+
+	waitersMu sync.Mutex
+	waiters   []chan decodeResult
+	// forcedTimeoutWaiter is the waiter, if any, that a forced local
+	// SetReadDeadline (see awaitResponse) is currently trying to unblock.
+	// Resetting that deadline races with readLoop, which can observe more
+	// than one timed-out read per forced deadline; only the first such
+	// read - the one this field is still set for - is the genuine
+	// cancellation notice and may be delivered (and dequeued). Every
+	// later one is a spurious echo of the same already-handled deadline
+	// and must be ignored outright, or it could be mistaken for a
+	// subsequently registered, unrelated command's waiter.
+	forcedTimeoutWaiter chan decodeResult
+	// pendingDrains counts responses that readLoop must discard, rather
+	// than hand to the next queued waiter, because they belong to a
+	// command whose Dispatch already gave up on a forced local timeout.
+	// awaitResponse increments it once per cancellation, before forcing
+	// the deadline; readLoop decrements it as each corresponding real
+	// response is drained.
+	pendingDrains int
+	// deadErr is set once the reader loop exits after a non-timeout
+	// transport failure; every Dispatch from then on fails fast with it.
+	deadErr error
+
+	// onDispatch, when set, is invoked after every command/response round
+	// trip, for logging and instrumentation.
+	onDispatch func(cmd []string, resp *Response, dur time.Duration)
+}
+
+// NewChannel wraps r/w in a Channel using codec to frame commands and
+// decode responses. If conn is non-nil, a per-request context deadline is
+// applied to it via SetReadDeadline, allowing a blocked read to be
+// interrupted on cancellation; codec defaults to AsteriskCodec if nil.
+func NewChannel(r io.Reader, w io.Writer, conn net.Conn, codec Codec) *Channel {
+	if codec == nil {
+		codec = AsteriskCodec{}
+	}
+	return &Channel{
+		r:     bufio.NewReader(r),
+		w:     w,
+		conn:  conn,
+		codec: codec,
+	}
+}
+
+// startReader lazily launches the background goroutine that decodes
+// responses from r and delivers them to registered waiters, so a pending
+// read can be unblocked without leaking a goroutine per Dispatch call.
+func (c *Channel) startReader() {
+	c.readerOnce.Do(func() {
+		go c.readLoop()
+	})
+}
+
+// readLoop decodes one response at a time and delivers each to the oldest
+// registered waiter. A timeout induced by a per-request deadline (set by
+// awaitResponse, below) does not end the loop, since the connection itself
+// is still good; only a genuine transport failure does, at which point
+// every still-pending waiter is failed and deadErr is recorded for any
+// Dispatch that registers afterward.
+//
+// A forced local timeout leaves the peer still working on the cancelled
+// command, so the real response for it is still coming; that response
+// must not be handed to whatever waiter now happens to be at the head of
+// the queue, or it will be mismatched onto a later, unrelated command (and
+// that later command's own response dropped with no waiter left to take
+// it). deliverForcedTimeout and drainPending, below, keep that from
+// happening on both ends: the synthetic timeout itself, and the stray real
+// response that eventually follows it.
+func (c *Channel) readLoop() {
+	for {
+		resp, err := c.codec.DecodeResponse(c.r)
+
+		if err != nil && isTimeout(err) {
+			c.deliverForcedTimeout(err)
+			continue
+		}
+
+		if err == nil && c.drainPending() {
+			continue
+		}
+
+		c.deliver(decodeResult{resp: resp, err: err})
+
+		if err != nil {
+			c.waitersMu.Lock()
+			c.deadErr = err
+			pending := c.waiters
+			c.waiters = nil
+			c.waitersMu.Unlock()
+
+			for _, w := range pending {
+				w <- decodeResult{err: err}
+			}
+			return
+		}
+	}
+}
+
+// deliverForcedTimeout hands err to forcedTimeoutWaiter and dequeues it,
+// if one is set, then clears the field. Only the first timed-out read
+// following a forced local cancellation is genuine; any further one finds
+// forcedTimeoutWaiter already nil (cleared here) and is a no-op, rather
+// than risking a waiter registered by a later, unrelated command.
+func (c *Channel) deliverForcedTimeout(err error) {
+	c.waitersMu.Lock()
+	w := c.forcedTimeoutWaiter
+	c.forcedTimeoutWaiter = nil
+	if w != nil && len(c.waiters) > 0 {
+		c.waiters = c.waiters[1:]
+	}
+	c.waitersMu.Unlock()
+
+	if w != nil {
+		w <- decodeResult{err: err}
+	}
+}
+
+// drainPending reports whether the response just decoded is owed to a
+// command that already gave up on a forced local timeout, and if so,
+// consumes one pendingDrains credit so the response is discarded instead
+// of delivered.
+func (c *Channel) drainPending() bool {
+	c.waitersMu.Lock()
+	defer c.waitersMu.Unlock()
+
+	if c.pendingDrains == 0 {
+		return false
+	}
+	c.pendingDrains--
+	return true
+}
+
+// deliver hands result to the oldest registered waiter, if any. A result
+// decoded with no waiter registered - an unsolicited HANGUP notification
+// received between commands - is simply dropped.
+func (c *Channel) deliver(result decodeResult) {
+	c.waitersMu.Lock()
+	var w chan decodeResult
+	if len(c.waiters) > 0 {
+		w = c.waiters[0]
+		c.waiters = c.waiters[1:]
+	}
+	c.waitersMu.Unlock()
+
+	if w != nil {
+		w <- result
+	}
+}
+
+// register enqueues a new waiter for the next decoded response, or returns
+// the reader's sticky transport error if it has already stopped.
+func (c *Channel) register() (chan decodeResult, error) {
+	c.waitersMu.Lock()
+	defer c.waitersMu.Unlock()
+
+	if c.deadErr != nil {
+		return nil, c.deadErr
+	}
+
+	w := make(chan decodeResult, 1)
+	c.waiters = append(c.waiters, w)
+	return w, nil
+}
+
+// isTimeout reports whether err was caused by a read deadline expiring, as
+// opposed to some other transport failure (closed connection, EOF, ...).
+func isTimeout(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+	return errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+// Dispatch encodes cmd, writes it, and returns the decoded response,
+// honoring ctx for per-request deadlines and cancellation. The returned
+// error is reserved for transport-level failures; domain-level failures
+// are carried in Response.Error, already wrapped as an *AGIError naming
+// cmd's Op/Args before onDispatch (if set) is invoked, so logging,
+// Metrics, Tracer, and the caller all observe the same final error rather
+// than racing a later wrap of the same Response.
+func (c *Channel) Dispatch(ctx context.Context, cmd []string) (resp *Response, err error) {
+	c.startReader()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			resp = &Response{Error: newAGIError(cmd, 0, "", err)}
+		} else if resp.Error != nil {
+			resp.Error = newAGIError(cmd, resp.Status, resp.RawLine, resp.Error)
+		}
+		if c.onDispatch != nil {
+			c.onDispatch(cmd, resp, time.Since(start))
+		}
+	}()
+
+	waiter, err := c.register()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.codec.EncodeCommand(c.w, cmd); err != nil {
+		return nil, fmt.Errorf("failed to send command: %w", err)
+	}
+
+	return c.awaitResponse(ctx, waiter)
+}
+
+// awaitResponse waits for waiter's decoded response, honoring ctx. When the
+// channel is backed by a net.Conn, ctx's deadline is applied via
+// SetReadDeadline; on cancellation, awaitResponse records waiter as
+// forcedTimeoutWaiter and forces a SetReadDeadline(time.Unix(1, 0)) to
+// unblock the reader goroutine, waiting for it to actually deliver the
+// resulting timeout to waiter before returning, so the reader is never
+// left mid-read for a command this call has given up on. The peer itself
+// was never told to cancel, so its real response for the command is still
+// in flight; a pendingDrains credit recorded at the same time makes
+// readLoop discard that response when it finally arrives instead of
+// handing it to whatever later command is now at the head of the waiter
+// queue.
+// Without a net.Conn (stdio/EAGI), there is no way to interrupt the
+// underlying blocking read, so cancellation is surfaced as soon as ctx is
+// done; the eventual (stale) delivery to waiter is simply never read, and -
+// because deliver() always targets the oldest queued waiter - can never be
+// mistaken for a later command's response.
+func (c *Channel) awaitResponse(ctx context.Context, waiter chan decodeResult) (*Response, error) {
+	if c.conn != nil {
+		// With no ctx deadline, dl is zero and clears any read deadline
+		// left over from Server's ReadTimeout/IdleTimeout, which only
+		// bound the time before a command, not the wait for its response.
+		dl, _ := ctx.Deadline()
+		_ = c.conn.SetReadDeadline(dl)
+		defer func() {
+			_ = c.conn.SetReadDeadline(time.Time{})
+		}()
+	}
+
+	select {
+	case result := <-waiter:
+		return result.resp, result.err
+	case <-ctx.Done():
+		if c.conn != nil {
+			c.waitersMu.Lock()
+			c.forcedTimeoutWaiter = waiter
+			c.pendingDrains++
+			c.waitersMu.Unlock()
+
+			_ = c.conn.SetReadDeadline(time.Unix(1, 0))
+			<-waiter
+		}
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,85 @@
+package agi
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePeer emulates the remote side of a net.Conn-backed AGI session,
+// giving a test control over exactly when a response is written back,
+// independent of when the corresponding command is read.
+type fakePeer struct {
+	conn net.Conn
+	mu   sync.Mutex
+}
+
+func (p *fakePeer) writeLine(line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, _ = p.conn.Write([]byte(line + "\n"))
+}
+
+// TestChannel_CancelledCommandDoesNotStealNextResponse reproduces, on the
+// net.Conn path used by every FastAGI Server session (see server.go), the
+// desync the 058ed84 fix commit addressed for stdio: a command whose ctx
+// expires before the peer answers must not have its eventual, real
+// response handed to the next, unrelated command once it finally arrives.
+func TestChannel_CancelledCommandDoesNotStealNextResponse(t *testing.T) {
+	clientConn, peerConn := net.Pipe()
+	defer clientConn.Close()
+	defer peerConn.Close()
+
+	ch := NewChannel(clientConn, clientConn, clientConn, nil)
+	peer := &fakePeer{conn: peerConn}
+	scanner := bufio.NewScanner(peerConn)
+
+	go func() {
+		// A real AGI peer is strictly synchronous: it only reads the next
+		// command line once it has finished answering the current one,
+		// regardless of whether the client gave up on that answer
+		// locally. SLOWCMD's response is deliberately held back to
+		// arrive well after the client has already moved on to FASTCMD.
+		if !scanner.Scan() || !strings.HasPrefix(scanner.Text(), "SLOWCMD") {
+			return
+		}
+		time.Sleep(250 * time.Millisecond)
+		peer.writeLine("200 result=1")
+
+		if !scanner.Scan() || !strings.HasPrefix(scanner.Text(), "FASTCMD") {
+			return
+		}
+		peer.writeLine("200 result=2")
+
+		if !scanner.Scan() || !strings.HasPrefix(scanner.Text(), "PING") {
+			return
+		}
+		peer.writeLine("200 result=3")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := ch.Dispatch(ctx, []string{"SLOWCMD"}); err == nil {
+		t.Fatal("expected SLOWCMD to time out")
+	}
+
+	resp, err := ch.Dispatch(context.Background(), []string{"FASTCMD"})
+	if err != nil {
+		t.Fatalf("FASTCMD: unexpected transport error: %v", err)
+	}
+	if resp.Result != 2 {
+		t.Fatalf("FASTCMD: got result=%d, want 2 (stolen SLOWCMD response)", resp.Result)
+	}
+
+	resp, err = ch.Dispatch(context.Background(), []string{"PING"})
+	if err != nil {
+		t.Fatalf("PING: unexpected transport error: %v", err)
+	}
+	if resp.Result != 3 {
+		t.Fatalf("PING: got result=%d, want 3", resp.Result)
+	}
+}
@@ -0,0 +1,182 @@
+package agi
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Codec defines how AGI commands are framed on the wire: how a command is
+// encoded, and how the response that follows - which may span several
+// lines, as with a 520 usage message - is decoded back into a Response.
+//
+// Domain-level failures (hangup, 511, timeout, a non-200 status, a line
+// that cannot be parsed, ...) are carried in the returned Response's Error
+// field, as a sentinel such as ErrHangup where one applies; the error
+// DecodeResponse itself returns is reserved for transport-level failures
+// (a closed connection, a read timeout).
+type Codec interface {
+	// EncodeCommand writes cmd to w as a single AGI command line.
+	EncodeCommand(w io.Writer, cmd []string) error
+
+	// DecodeResponse reads and parses the next AGI response from r.
+	DecodeResponse(r *bufio.Reader) (*Response, error)
+}
+
+// AsteriskCodec is the default Codec, speaking the AGI dialect shipped by
+// Asterisk's res_agi: "STATUS result=VALUE (extra)" responses, the
+// asynchronous "HANGUP" notification, and multi-line "520-...  520 End of
+// proper usage." usage messages.
+type AsteriskCodec struct{}
+
+// EncodeCommand writes cmd as a single AGI command line to w.
+func (AsteriskCodec) EncodeCommand(w io.Writer, cmd []string) error {
+	_, err := w.Write([]byte(strings.Join(cmd, " ") + "\n"))
+	return err
+}
+
+// DecodeResponse reads and parses the next AGI response from r.
+func (AsteriskCodec) DecodeResponse(r *bufio.Reader) (*Response, error) {
+	resp := &Response{}
+
+	raw, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	resp.RawLine = raw
+
+	if strings.HasPrefix(raw, "HANGUP") {
+		resp.Error = ErrHangup
+		return resp, nil
+	}
+
+	if strings.HasPrefix(raw, "520-") {
+		body, err := readUsageBody(r, raw)
+		if err != nil {
+			return nil, err
+		}
+		resp.Status = StatusEndUsage
+		resp.Value = body
+		resp.Error = ErrInvalidCommandSyntax
+		return resp, nil
+	}
+
+	if raw == "" {
+		return resp, nil
+	}
+
+	pieces := responseRegex.FindStringSubmatch(raw)
+	if pieces == nil {
+		pieces = responseRegexOtherResponse.FindStringSubmatch(raw)
+	}
+	if pieces == nil {
+		resp.Error = fmt.Errorf("failed to parse result: %s", raw)
+		return resp, nil
+	}
+
+	resp.Status, err = strconv.Atoi(pieces[1])
+	if err != nil {
+		resp.Error = fmt.Errorf("failed to get status code: %w", err)
+		return resp, nil
+	}
+
+	resp.ResultString = pieces[2]
+
+	if resp.Status == StatusDeadChannel {
+		resp.Error = Err511GenericError
+		if strings.EqualFold(resp.ResultString, Err511CommandNotPermitted.Error()) {
+			resp.Error = Err511CommandNotPermitted
+		}
+		return resp, nil
+	}
+
+	resp.Result, err = strconv.Atoi(pieces[2])
+	if err != nil {
+		// Not every command returns a numeric result (e.g. SPEECH-RESULTS);
+		// fall back to a non-zero placeholder rather than failing the call.
+		resp.Result = 1
+	}
+
+	// responseRegexOtherResponse has no third (extra value) group, so
+	// pieces may be shorter than responseRegex's four elements.
+	var extra string
+	if len(pieces) > 3 {
+		extra = pieces[3]
+	}
+
+	resp.Value, err = readValue(r, strings.TrimSpace(extra))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Value == "timeout" {
+		resp.Error = ErrTimeout
+	}
+
+	if resp.Status == StatusOK && resp.Value == "-1" {
+		resp.Error = ErrHangup
+		return resp, nil
+	}
+
+	if resp.Status != StatusOK && resp.Error == nil {
+		resp.Error = statusError(resp.Status)
+	}
+
+	return resp, nil
+}
+
+// responseRegex matches the common "STATUS result=VALUE (extra)" response
+// line. The result is intentionally permissive (letters, digits, `_` and
+// `*`) since not every AGI command returns a purely numeric result.
+var responseRegex = regexp.MustCompile(`^(\d{3})\sresult=(-?[[:alnum:]_*]*)(\s.*)?$`)
+
+// responseRegexOtherResponse matches non-"result=" response lines that
+// some commands (and Asterisk versions) emit instead, e.g. "200 active".
+var responseRegexOtherResponse = regexp.MustCompile(`^(\d{3})\s([\s\w]+)$`)
+
+// endOfProperUsage is the line terminating a multi-line 520 usage message.
+const endOfProperUsage = "520 End of proper usage."
+
+// readUsageBody accumulates the lines of a "520-..." multi-line usage
+// message, starting with the already-read first line, until the
+// terminating "520 End of proper usage." line is seen.
+func readUsageBody(r *bufio.Reader, first string) (string, error) {
+	lines := []string{strings.TrimPrefix(first, "520-")}
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return "", err
+		}
+		if line == endOfProperUsage {
+			return strings.Join(lines, "\n"), nil
+		}
+		lines = append(lines, strings.TrimPrefix(line, "520-"))
+	}
+}
+
+// readValue strips the wrapping parentheses from a response's value field,
+// pulling in further lines if the value contains embedded newlines (i.e.
+// the closing paren hasn't been seen yet).
+func readValue(r *bufio.Reader, wrapped string) (string, error) {
+	for strings.HasPrefix(wrapped, "(") && !strings.Contains(wrapped, ")") {
+		next, err := readLine(r)
+		if err != nil {
+			return "", err
+		}
+		wrapped += "\n" + next
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(wrapped, "("), ")"), nil
+}
+
+// readLine reads a single newline-terminated line from r, trimming the
+// trailing "\r\n"/"\n".
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
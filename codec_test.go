@@ -0,0 +1,102 @@
+package agi
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, raw string) *Response {
+	t.Helper()
+	resp, err := AsteriskCodec{}.DecodeResponse(bufio.NewReader(strings.NewReader(raw)))
+	if err != nil {
+		t.Fatalf("DecodeResponse: unexpected transport error: %v", err)
+	}
+	return resp
+}
+
+func TestAsteriskCodec_DecodeResponse_Result(t *testing.T) {
+	resp := decode(t, "200 result=1\n")
+	if resp.Status != StatusOK || resp.Result != 1 || resp.ResultString != "1" {
+		t.Fatalf("got Status=%d Result=%d ResultString=%q, want 200/1/\"1\"", resp.Status, resp.Result, resp.ResultString)
+	}
+	if resp.Error != nil {
+		t.Fatalf("unexpected Error: %v", resp.Error)
+	}
+	if resp.RawLine != "200 result=1" {
+		t.Fatalf("RawLine = %q, want the literal wire line", resp.RawLine)
+	}
+}
+
+func TestAsteriskCodec_DecodeResponse_ResultWithExtra(t *testing.T) {
+	resp := decode(t, "200 result=0 (timeout)\n")
+	if resp.Value != "timeout" {
+		t.Fatalf("Value = %q, want %q", resp.Value, "timeout")
+	}
+	if !errors.Is(resp.Error, ErrTimeout) {
+		t.Fatalf("Error = %v, want ErrTimeout", resp.Error)
+	}
+}
+
+func TestAsteriskCodec_DecodeResponse_Hangup(t *testing.T) {
+	resp := decode(t, "HANGUP\n")
+	if !errors.Is(resp.Error, ErrHangup) {
+		t.Fatalf("Error = %v, want ErrHangup", resp.Error)
+	}
+	if resp.RawLine != "HANGUP" {
+		t.Fatalf("RawLine = %q, want %q", resp.RawLine, "HANGUP")
+	}
+}
+
+func TestAsteriskCodec_DecodeResponse_DeadChannel(t *testing.T) {
+	resp := decode(t, "511 Command Not Permitted on a dead channel or intercept routine\n")
+	if !errors.Is(resp.Error, Err511CommandNotPermitted) {
+		t.Fatalf("Error = %v, want Err511CommandNotPermitted", resp.Error)
+	}
+	if resp.RawLine != "511 Command Not Permitted on a dead channel or intercept routine" {
+		t.Fatalf("RawLine = %q, want the literal wire line, not a reconstructed one", resp.RawLine)
+	}
+}
+
+// TestAsteriskCodec_DecodeResponse_OtherResponseShortMatch pins the
+// chunk0-3 fix (ae95ea8): responseRegexOtherResponse has no third capture
+// group, so a response like "200 active" that only matches it must not
+// panic indexing pieces[3].
+func TestAsteriskCodec_DecodeResponse_OtherResponseShortMatch(t *testing.T) {
+	resp := decode(t, "200 active\n")
+	if resp.Status != StatusOK {
+		t.Fatalf("Status = %d, want 200", resp.Status)
+	}
+	if resp.Value != "" {
+		t.Fatalf("Value = %q, want empty", resp.Value)
+	}
+}
+
+func TestAsteriskCodec_DecodeResponse_UsageMultiLine(t *testing.T) {
+	raw := "520-Usage: STREAM FILE <filename> <escape digits> [offset]\n" +
+		"520 End of proper usage.\n"
+	resp := decode(t, raw)
+	if resp.Status != StatusEndUsage {
+		t.Fatalf("Status = %d, want %d", resp.Status, StatusEndUsage)
+	}
+	if !errors.Is(resp.Error, ErrInvalidCommandSyntax) {
+		t.Fatalf("Error = %v, want ErrInvalidCommandSyntax", resp.Error)
+	}
+	if resp.Value != "Usage: STREAM FILE <filename> <escape digits> [offset]" {
+		t.Fatalf("Value = %q, unexpected usage body", resp.Value)
+	}
+	if strings.Contains(resp.RawLine, "\n") {
+		t.Fatalf("RawLine = %q, must not embed the multi-line usage body", resp.RawLine)
+	}
+}
+
+func TestAsteriskCodec_EncodeCommand(t *testing.T) {
+	var buf strings.Builder
+	if err := (AsteriskCodec{}).EncodeCommand(&buf, []string{"STREAM FILE", "welcome", "1234"}); err != nil {
+		t.Fatalf("EncodeCommand: unexpected error: %v", err)
+	}
+	if got, want := buf.String(), "STREAM FILE welcome 1234\n"; got != want {
+		t.Fatalf("EncodeCommand wrote %q, want %q", got, want)
+	}
+}
@@ -1,6 +1,7 @@
 package agi
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -10,14 +11,19 @@ import (
 	"go.uber.org/zap"
 )
 
+// AnswerContext answers the channel, honoring ctx.
+func (a *AGI) AnswerContext(ctx context.Context) error {
+	return a.CommandContext(ctx, "ANSWER").Err()
+}
+
 // Answer answers the channel
 func (a *AGI) Answer() error {
-	return a.Command("ANSWER").Err()
+	return a.AnswerContext(context.Background())
 }
 
-// Status returns the channel status
-func (a *AGI) Status() (State, error) {
-	r, err := a.Command("CHANNEL STATUS").Res()
+// StatusContext returns the channel status, honoring ctx.
+func (a *AGI) StatusContext(ctx context.Context) (State, error) {
+	r, err := a.CommandContext(ctx, "CHANNEL STATUS").Res()
 	if err != nil {
 		return StateDown, err
 	}
@@ -28,29 +34,54 @@ func (a *AGI) Status() (State, error) {
 	return State(state), nil
 }
 
+// Status returns the channel status
+func (a *AGI) Status() (State, error) {
+	return a.StatusContext(context.Background())
+}
+
+// ExecContext runs a dialplan application, honoring ctx.
+func (a *AGI) ExecContext(ctx context.Context, cmd ...string) (string, error) {
+	cmd = append([]string{"EXEC"}, cmd...)
+	return a.CommandContext(ctx, cmd...).Val()
+}
+
 // Exec runs a dialplan application
 func (a *AGI) Exec(cmd ...string) (string, error) {
-	cmd = append([]string{"EXEC"}, cmd...)
-	return a.Command(cmd...).Val()
+	return a.ExecContext(context.Background(), cmd...)
+}
+
+// GetContext gets the value of the given channel variable, honoring ctx.
+func (a *AGI) GetContext(ctx context.Context, key string) (string, error) {
+	return a.CommandContext(ctx, "GET VARIABLE", key).Val()
 }
 
 // Get gets the value of the given channel variable
 func (a *AGI) Get(key string) (string, error) {
-	return a.Command("GET VARIABLE", key).Val()
+	return a.GetContext(context.Background(), key)
 }
 
-// GetData plays a file and receives DTMF, returning the received digits
-func (a *AGI) GetData(sound string, timeout time.Duration, maxDigits int) (digits string, err error) {
+// GetDataContext plays a file and receives DTMF, returning the received digits, honoring ctx.
+func (a *AGI) GetDataContext(ctx context.Context, sound string, timeout time.Duration, maxDigits int) (digits string, err error) {
 	if sound == "" {
 		sound = "silence/1"
 	}
-	resp := a.CommandNoParse("GET DATA", sound, toMSec(timeout), strconv.Itoa(maxDigits))
+	resp := a.CommandNoParseContext(ctx, "GET DATA", sound, toMSec(timeout), strconv.Itoa(maxDigits))
 	return resp.Res()
 }
 
+// GetData plays a file and receives DTMF, returning the received digits
+func (a *AGI) GetData(sound string, timeout time.Duration, maxDigits int) (digits string, err error) {
+	return a.GetDataContext(context.Background(), sound, timeout, maxDigits)
+}
+
+// HangupContext terminates the call, honoring ctx.
+func (a *AGI) HangupContext(ctx context.Context) error {
+	return a.CommandContext(ctx, "HANGUP").Err()
+}
+
 // Hangup terminates the call
 func (a *AGI) Hangup() error {
-	return a.Command("HANGUP").Err()
+	return a.HangupContext(context.Background())
 }
 
 // RecordOptions describes the options available when recording
@@ -74,8 +105,8 @@ type RecordOptions struct {
 	Offset int
 }
 
-// Record records audio to a file
-func (a *AGI) Record(name string, opts *RecordOptions) error {
+// RecordContext records audio to a file, honoring ctx.
+func (a *AGI) RecordContext(ctx context.Context, name string, opts *RecordOptions) error {
 	if opts == nil {
 		opts = &RecordOptions{}
 	}
@@ -89,58 +120,78 @@ func (a *AGI) Record(name string, opts *RecordOptions) error {
 		opts.Timeout = 5 * time.Minute
 	}
 
-	cmd := strings.Join([]string{
-		"RECORD FILE ",
+	cmd := []string{
+		"RECORD FILE",
 		name,
 		opts.Format,
 		opts.EscapeDigits,
 		toMSec(opts.Timeout),
-	}, " ")
+	}
 
 	if opts.Offset > 0 {
-		cmd += " " + strconv.Itoa(opts.Offset)
+		cmd = append(cmd, strconv.Itoa(opts.Offset))
 	}
 
 	if opts.Beep {
-		cmd += " BEEP"
+		cmd = append(cmd, "BEEP")
 	}
 
 	if opts.Silence > 0 {
-		cmd += " s=" + toSec(opts.Silence)
+		cmd = append(cmd, "s="+toSec(opts.Silence))
 	}
 
-	return a.Command(cmd).Err()
+	return a.CommandContext(ctx, cmd...).Err()
+}
+
+// Record records audio to a file
+func (a *AGI) Record(name string, opts *RecordOptions) error {
+	return a.RecordContext(context.Background(), name, opts)
+}
+
+// SayAlphaContext plays a character string, annunciating each character, honoring ctx.
+func (a *AGI) SayAlphaContext(ctx context.Context, label string, escapeDigits string) (digit string, err error) {
+	// NOTE: AGI needs empty double quotes hold the place of the empty value in the line
+	if escapeDigits == "" {
+		escapeDigits = `""`
+	}
+	return a.CommandContext(ctx, "SAY ALPHA", label, escapeDigits).Val()
 }
 
 // SayAlpha plays a character string, annunciating each character.
 func (a *AGI) SayAlpha(label string, escapeDigits string) (digit string, err error) {
+	return a.SayAlphaContext(context.Background(), label, escapeDigits)
+}
+
+// SayDigitsContext plays a digit string, annunciating each digit, honoring ctx.
+func (a *AGI) SayDigitsContext(ctx context.Context, number string, escapeDigits string) (digit string, err error) {
 	// NOTE: AGI needs empty double quotes hold the place of the empty value in the line
 	if escapeDigits == "" {
 		escapeDigits = `""`
 	}
-	return a.Command("SAY ALPHA", label, escapeDigits).Val()
+	return a.CommandContext(ctx, "SAY DIGITS", number, escapeDigits).Val()
 }
 
 // SayDigits plays a digit string, annunciating each digit.
 func (a *AGI) SayDigits(number string, escapeDigits string) (digit string, err error) {
+	return a.SayDigitsContext(context.Background(), number, escapeDigits)
+}
+
+// SayDateContext plays a date, honoring ctx.
+func (a *AGI) SayDateContext(ctx context.Context, when time.Time, escapeDigits string) (digit string, err error) {
 	// NOTE: AGI needs empty double quotes hold the place of the empty value in the line
 	if escapeDigits == "" {
 		escapeDigits = `""`
 	}
-	return a.Command("SAY DIGITS", number, escapeDigits).Val()
+	return a.CommandContext(ctx, "SAY DATE", toEpoch(when), escapeDigits).Val()
 }
 
 // SayDate plays a date
 func (a *AGI) SayDate(when time.Time, escapeDigits string) (digit string, err error) {
-	// NOTE: AGI needs empty double quotes hold the place of the empty value in the line
-	if escapeDigits == "" {
-		escapeDigits = `""`
-	}
-	return a.Command("SAY DATE", toEpoch(when), escapeDigits).Val()
+	return a.SayDateContext(context.Background(), when, escapeDigits)
 }
 
-// SayDateTime plays a date using the given format.  See `voicemail.conf` for the format syntax; defaults to `ABdY 'digits/at' IMp`.
-func (a *AGI) SayDateTime(when time.Time, escapeDigits string, format string) (digit string, err error) {
+// SayDateTimeContext plays a date using the given format, honoring ctx.  See `voicemail.conf` for the format syntax; defaults to `ABdY 'digits/at' IMp`.
+func (a *AGI) SayDateTimeContext(ctx context.Context, when time.Time, escapeDigits string, format string) (digit string, err error) {
 	// Extract the timezone from the time
 	zone, _ := when.Zone()
 
@@ -154,100 +205,176 @@ func (a *AGI) SayDateTime(when time.Time, escapeDigits string, format string) (d
 		format = "ABdY 'digits/at' IMp"
 	}
 
-	return a.Command("SAY DATETIME", toEpoch(when), escapeDigits, format, zone).Val()
+	return a.CommandContext(ctx, "SAY DATETIME", toEpoch(when), escapeDigits, format, zone).Val()
+}
+
+// SayDateTime plays a date using the given format.  See `voicemail.conf` for the format syntax; defaults to `ABdY 'digits/at' IMp`.
+func (a *AGI) SayDateTime(when time.Time, escapeDigits string, format string) (digit string, err error) {
+	return a.SayDateTimeContext(context.Background(), when, escapeDigits, format)
+}
+
+// SayNumberContext plays the given number, honoring ctx.
+func (a *AGI) SayNumberContext(ctx context.Context, number string, escapeDigits string) (digit string, err error) {
+	// NOTE: AGI needs empty double quotes hold the place of the empty value in the line
+	if escapeDigits == "" {
+		escapeDigits = `""`
+	}
+	return a.CommandContext(ctx, "SAY NUMBER", number, escapeDigits).Val()
 }
 
 // SayNumber plays the given number.
 func (a *AGI) SayNumber(number string, escapeDigits string) (digit string, err error) {
+	return a.SayNumberContext(context.Background(), number, escapeDigits)
+}
+
+// SayPhoneticContext plays the given phrase phonetically, honoring ctx.
+func (a *AGI) SayPhoneticContext(ctx context.Context, phrase string, escapeDigits string) (digit string, err error) {
 	// NOTE: AGI needs empty double quotes hold the place of the empty value in the line
 	if escapeDigits == "" {
 		escapeDigits = `""`
 	}
-	return a.Command("SAY NUMBER", number, escapeDigits).Val()
+	return a.CommandContext(ctx, "SAY PHONETIC", phrase, escapeDigits).Val()
 }
 
 // SayPhonetic plays the given phrase phonetically
 func (a *AGI) SayPhonetic(phrase string, escapeDigits string) (digit string, err error) {
+	return a.SayPhoneticContext(context.Background(), phrase, escapeDigits)
+}
+
+// SayTimeContext plays the time part of the given timestamp, honoring ctx.
+func (a *AGI) SayTimeContext(ctx context.Context, when time.Time, escapeDigits string) (digit string, err error) {
 	// NOTE: AGI needs empty double quotes hold the place of the empty value in the line
 	if escapeDigits == "" {
 		escapeDigits = `""`
 	}
-	return a.Command("SAY PHOENTIC", phrase, escapeDigits).Val()
+	return a.CommandContext(ctx, "SAY TIME", toEpoch(when), escapeDigits).Val()
 }
 
 // SayTime plays the time part of the given timestamp
 func (a *AGI) SayTime(when time.Time, escapeDigits string) (digit string, err error) {
-	// NOTE: AGI needs empty double quotes hold the place of the empty value in the line
-	if escapeDigits == "" {
-		escapeDigits = `""`
-	}
-	return a.Command("SAY TIME", toEpoch(when), escapeDigits).Val()
+	return a.SayTimeContext(context.Background(), when, escapeDigits)
+}
+
+// SetContext sets the given channel variable to
+// the provided value, honoring ctx.
+func (a *AGI) SetContext(ctx context.Context, key, val string) error {
+	return a.CommandContext(ctx, "SET VARIABLE", key, val).Err()
 }
 
 // Set sets the given channel variable to
 // the provided value.
 func (a *AGI) Set(key, val string) error {
-	return a.Command("SET VARIABLE", key, val).Err()
+	return a.SetContext(context.Background(), key, val)
+}
+
+// SetRawContext sets the given channel settings to
+// the provided value, honoring ctx.
+func (a *AGI) SetRawContext(ctx context.Context, key, val string) error {
+	return a.CommandContext(ctx, "SET", key, val).Err()
 }
 
 // SetRaw sets the given channel settings to
 // the provided value.
 func (a *AGI) SetRaw(key, val string) error {
-	return a.Command("SET", key, val).Err()
+	return a.SetRawContext(context.Background(), key, val)
 }
 
-// StreamFile plays the given file to the channel
-func (a *AGI) StreamFile(name string, escapeDigits string, offset int) (digit string, err error) {
+// StreamFileContext plays the given file to the channel, honoring ctx.
+func (a *AGI) StreamFileContext(ctx context.Context, name string, escapeDigits string, offset int) (digit string, err error) {
 	// NOTE: AGI needs empty double quotes hold the place of the empty value in the line
 	if escapeDigits == "" {
 		escapeDigits = `""`
 	}
-	return a.Command("STREAM FILE", name, escapeDigits, strconv.Itoa(offset)).Val()
+	return a.CommandContext(ctx, "STREAM FILE", name, escapeDigits, strconv.Itoa(offset)).Val()
+}
+
+// StreamFile plays the given file to the channel
+func (a *AGI) StreamFile(name string, escapeDigits string, offset int) (digit string, err error) {
+	return a.StreamFileContext(context.Background(), name, escapeDigits, offset)
+}
+
+// VerboseContext logs the given message to the verbose message system, honoring ctx.
+func (a *AGI) VerboseContext(ctx context.Context, msg string, level int) error {
+	return a.CommandContext(ctx, "VERBOSE", strconv.Quote(msg), strconv.Itoa(level)).Err()
 }
 
 // Verbose logs the given message to the verbose message system
 func (a *AGI) Verbose(msg string, level int) error {
-	return a.Command("VERBOSE", strconv.Quote(msg), strconv.Itoa(level)).Err()
+	return a.VerboseContext(context.Background(), msg, level)
+}
+
+// VerbosefContext logs the formatted verbose output, honoring ctx.
+func (a *AGI) VerbosefContext(ctx context.Context, format string, args ...interface{}) error {
+	return a.VerboseContext(ctx, fmt.Sprintf(format, args...), 9)
 }
 
 // Verbosef logs the formatted verbose output
 func (a *AGI) Verbosef(format string, args ...interface{}) error {
-	return a.Verbose(fmt.Sprintf(format, args...), 9)
+	return a.VerbosefContext(context.Background(), format, args...)
+}
+
+// LogContext sends an arbitrary text message to a selected log level, honoring ctx.
+func (a *AGI) LogContext(ctx context.Context, logLevel, msg string) error {
+	_, err := a.ExecContext(ctx, "Log", strings.ToUpper(logLevel), msg)
+	return err
 }
 
 // Log Sends an arbitrary text message to a selected log level
 func (a *AGI) Log(logLevel, msg string) error {
-	_, err := a.Exec("Log", strings.ToUpper(logLevel), msg)
-	return err
+	return a.LogContext(context.Background(), logLevel, msg)
+}
+
+func (a *AGI) LogErrorContext(ctx context.Context, msg string) error {
+	return a.LogContext(ctx, "ERROR", msg)
 }
 
 func (a *AGI) LogError(msg string) error {
-	return a.Log("ERROR", msg)
+	return a.LogErrorContext(context.Background(), msg)
+}
+
+func (a *AGI) LogWarningContext(ctx context.Context, msg string) error {
+	return a.LogContext(ctx, "WARNING", msg)
 }
 
 func (a *AGI) LogWarning(msg string) error {
-	return a.Log("WARNING", msg)
+	return a.LogWarningContext(context.Background(), msg)
+}
+
+func (a *AGI) LogNoticeContext(ctx context.Context, msg string) error {
+	return a.LogContext(ctx, "NOTICE", msg)
 }
 
 func (a *AGI) LogNotice(msg string) error {
-	return a.Log("NOTICE", msg)
+	return a.LogNoticeContext(context.Background(), msg)
+}
+
+func (a *AGI) LogDebugContext(ctx context.Context, msg string) error {
+	return a.LogContext(ctx, "DEBUG", msg)
 }
 
 func (a *AGI) LogDebug(msg string) error {
-	return a.Log("DEBUG", msg)
+	return a.LogDebugContext(context.Background(), msg)
+}
+
+func (a *AGI) LogVerboseContext(ctx context.Context, msg string) error {
+	return a.LogContext(ctx, "VERBOSE", msg)
 }
 
 func (a *AGI) LogVerbose(msg string) error {
-	return a.Log("VERBOSE", msg)
+	return a.LogVerboseContext(context.Background(), msg)
+}
+
+func (a *AGI) LogDTMFContext(ctx context.Context, msg string) error {
+	return a.LogContext(ctx, "DTMF", msg)
 }
 
 func (a *AGI) LogDTMF(msg string) error {
-	return a.Log("DTMF", msg)
+	return a.LogDTMFContext(context.Background(), msg)
 }
 
-// WaitForDigit waits for a DTMF digit and returns what is received
-func (a *AGI) WaitForDigit(timeout time.Duration) (digit string, err error) {
-	resp := a.Command("WAIT FOR DIGIT", toMSec(timeout))
+// WaitForDigitContext waits for a DTMF digit and returns what is received, honoring ctx.
+func (a *AGI) WaitForDigitContext(ctx context.Context, timeout time.Duration) (digit string, err error) {
+	resp := a.CommandContext(ctx, "WAIT FOR DIGIT", toMSec(timeout))
 	resp.ResultString = ""
 	if resp.Error == nil && strconv.IsPrint(rune(resp.Result)) {
 		resp.ResultString = strconv.Itoa(resp.Result)
@@ -255,8 +382,13 @@ func (a *AGI) WaitForDigit(timeout time.Duration) (digit string, err error) {
 	return resp.Res()
 }
 
-// WaitForSilence waits for a specified amount of silence
-func (a *AGI) WaitForSilence(silenceRequiredMsec int, iterations int, timeout time.Duration) (string, error) {
+// WaitForDigit waits for a DTMF digit and returns what is received
+func (a *AGI) WaitForDigit(timeout time.Duration) (digit string, err error) {
+	return a.WaitForDigitContext(context.Background(), timeout)
+}
+
+// WaitForSilenceContext waits for a specified amount of silence, honoring ctx.
+func (a *AGI) WaitForSilenceContext(ctx context.Context, silenceRequiredMsec int, iterations int, timeout time.Duration) (string, error) {
 	execCmd := []string{
 		"WaitForSilence",
 		strconv.Itoa(silenceRequiredMsec),
@@ -267,39 +399,54 @@ func (a *AGI) WaitForSilence(silenceRequiredMsec int, iterations int, timeout ti
 		execCmd = append(execCmd, toSec(timeout))
 	}
 
-	if _, err := a.Exec(execCmd...); err != nil {
+	if _, err := a.ExecContext(ctx, execCmd...); err != nil {
 		return "", err
 	}
 
-	return a.Get("WAITSTATUS")
+	return a.GetContext(ctx, "WAITSTATUS")
 }
 
-// ExecPlayback plays back given filenames
-func (a *AGI) ExecPlayback(filePath ...string) (string, error) {
+// WaitForSilence waits for a specified amount of silence
+func (a *AGI) WaitForSilence(silenceRequiredMsec int, iterations int, timeout time.Duration) (string, error) {
+	return a.WaitForSilenceContext(context.Background(), silenceRequiredMsec, iterations, timeout)
+}
+
+// ExecPlaybackContext plays back given filenames, honoring ctx.
+func (a *AGI) ExecPlaybackContext(ctx context.Context, filePath ...string) (string, error) {
 	execCmd := []string{
 		"Playback",
 		strings.Join(filePath, "&"),
 	}
 
-	if _, err := a.Exec(execCmd...); err != nil {
+	if _, err := a.ExecContext(ctx, execCmd...); err != nil {
 		return "", err
 	}
 
-	return a.Get("PLAYBACKSTATUS")
+	return a.GetContext(ctx, "PLAYBACKSTATUS")
 }
 
-// ExecBackground play a given audio filenames while waiting for digits of an extension to go to.
-func (a *AGI) ExecBackground(filePath ...string) (string, error) {
+// ExecPlayback plays back given filenames
+func (a *AGI) ExecPlayback(filePath ...string) (string, error) {
+	return a.ExecPlaybackContext(context.Background(), filePath...)
+}
+
+// ExecBackgroundContext plays a given audio filenames while waiting for digits of an extension to go to, honoring ctx.
+func (a *AGI) ExecBackgroundContext(ctx context.Context, filePath ...string) (string, error) {
 	execCmd := []string{
 		"BackGround",
 		strings.Join(filePath, "&"),
 	}
 
-	if _, err := a.Exec(execCmd...); err != nil {
+	if _, err := a.ExecContext(ctx, execCmd...); err != nil {
 		return "", err
 	}
 
-	return a.Get("BACKGROUNDSTATUS")
+	return a.GetContext(ctx, "BACKGROUNDSTATUS")
+}
+
+// ExecBackground play a given audio filenames while waiting for digits of an extension to go to.
+func (a *AGI) ExecBackground(filePath ...string) (string, error) {
+	return a.ExecBackgroundContext(context.Background(), filePath...)
 }
 
 // SetLogger setup external logger for low-level logging
@@ -2,6 +2,8 @@ package agi
 
 import (
 	"errors"
+	"strconv"
+	"strings"
 )
 
 // ErrHangup indicates the channel hung up during processing
@@ -10,8 +12,106 @@ var ErrHangup = errors.New("hangup")
 // ErrTimeout indicates the get data command ends with timeout during processing
 var ErrTimeout = errors.New("timeout")
 
+// ErrInvalidCommand indicates we have received a 510 status, meaning
+// Asterisk did not understand the command.
+var ErrInvalidCommand = errors.New("invalid or unknown command")
+
+// ErrInvalidCommandSyntax indicates we have received a 520 status, meaning
+// the command was invoked with improper syntax.
+var ErrInvalidCommandSyntax = errors.New("invalid command syntax")
+
 // Err511CommandNotPermitted indicates we have received error 511 Command Not Permitted
 var Err511CommandNotPermitted = errors.New("Command Not Permitted on a dead channel or intercept routine")
 
 // Err511GenericError indicates we have received generic 511 error
 var Err511GenericError = errors.New("Generic 511 Error")
+
+// ErrServerClosed is returned by Server.Serve, ListenAndServe, and
+// ListenAndServeTLS after Shutdown has closed the listener(s).
+var ErrServerClosed = errors.New("agi: Server closed")
+
+// AGIError describes a failed AGI command, echoing the command, its
+// arguments, and the status/response line that produced the failure, in
+// the style of os.PathError.
+type AGIError struct {
+	// Op is the AGI command verb, e.g. "STREAM FILE".
+	Op string
+
+	// Args are the arguments passed alongside Op.
+	Args []string
+
+	// Status is the AGI status code received, if any was parsed.
+	Status int
+
+	// RawLine is the raw response line received from Asterisk, if any.
+	RawLine string
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements the error interface, formatting as e.g.:
+//
+//	agi: STREAM FILE welcome "" 0: 510 invalid or unknown command (510 Invalid or unknown command)
+//
+// with the trailing parenthetical - the raw response line that produced
+// the failure - omitted when RawLine is empty.
+func (e *AGIError) Error() string {
+	var b strings.Builder
+	b.WriteString("agi: ")
+	b.WriteString(e.Op)
+	for _, arg := range e.Args {
+		b.WriteByte(' ')
+		b.WriteString(arg)
+	}
+	b.WriteByte(':')
+	if e.Status != 0 {
+		b.WriteByte(' ')
+		b.WriteString(strconv.Itoa(e.Status))
+	}
+	if e.Err != nil {
+		b.WriteByte(' ')
+		b.WriteString(e.Err.Error())
+	}
+	if e.RawLine != "" {
+		b.WriteString(" (")
+		b.WriteString(e.RawLine)
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+// Unwrap returns the underlying error, allowing errors.Is(err, ErrHangup)
+// and similar sentinel checks to see through an AGIError.
+func (e *AGIError) Unwrap() error {
+	return e.Err
+}
+
+// newAGIError wraps err with the command (cmd[0] as Op, the rest as Args),
+// status, and raw response line that produced it. It returns nil if err is
+// nil, so callers can pass it directly through error-returning assignments.
+func newAGIError(cmd []string, status int, rawLine string, err error) error {
+	if err == nil {
+		return nil
+	}
+	agiErr := &AGIError{Status: status, RawLine: rawLine, Err: err}
+	if len(cmd) > 0 {
+		agiErr.Op = cmd[0]
+		agiErr.Args = cmd[1:]
+	}
+	return agiErr
+}
+
+// statusError maps a non-200 AGI status code to its sentinel error.
+func statusError(status int) error {
+	switch status {
+	case StatusInvalid:
+		return ErrInvalidCommand
+	case StatusDeadChannel:
+		return Err511GenericError
+	case StatusEndUsage:
+		return ErrInvalidCommandSyntax
+	default:
+		return errors.New("non-200 status code")
+	}
+}
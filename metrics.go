@@ -0,0 +1,65 @@
+package agi
+
+import "time"
+
+// Metrics receives instrumentation from command dispatch and, for
+// sessions served by Server, from session lifecycle. An AGI session
+// defaults to a no-op implementation, and a nil Metrics passed to
+// AGI.SetMetrics is replaced with one; Server skips its Metrics hooks
+// while Server.Metrics is nil.
+type Metrics interface {
+	// CommandLatency records how long a single command round trip took.
+	CommandLatency(cmd string, d time.Duration)
+
+	// CommandCount increments the counter for a command, keyed by its
+	// name (e.g. "ANSWER", "STREAM FILE", "EXEC").
+	CommandCount(cmd string)
+
+	// StatusCount increments the counter for an AGI status code received
+	// in response to a command.
+	StatusCount(status int)
+
+	// HangupCount increments the counter for a hangup encountered during
+	// command dispatch.
+	HangupCount()
+
+	// ActiveSessions reports the current number of in-flight sessions;
+	// maintained by Server as connections are accepted and closed.
+	ActiveSessions(n int)
+}
+
+// Tracer receives per-session lifecycle and per-command events, letting
+// callers bridge AGI sessions to OpenTelemetry spans (or any other
+// tracing system) without this module depending on otel directly. A nil
+// Tracer (the default) is replaced with a no-op implementation.
+type Tracer interface {
+	// OnStart is called once a Tracer is attached to a session - for a
+	// session handed to a Server.Handler, once its initial variables have
+	// been read and before Handler runs.
+	OnStart(a *AGI)
+
+	// OnHangup is called when a command's response indicates the
+	// channel hung up.
+	OnHangup(a *AGI)
+
+	// OnCommand is called after every dispatched command completes,
+	// successfully or not.
+	OnCommand(cmd []string, resp *Response, dur time.Duration)
+}
+
+// noopMetrics is the zero-cost Metrics installed by newAGI, so the
+// dispatch path never needs a nil check.
+type noopMetrics struct{}
+
+func (noopMetrics) CommandLatency(string, time.Duration) {}
+func (noopMetrics) CommandCount(string)                  {}
+func (noopMetrics) StatusCount(int)                      {}
+func (noopMetrics) HangupCount()                         {}
+func (noopMetrics) ActiveSessions(int)                   {}
+
+// noopTracer is the zero-cost Tracer installed by newAGI.
+type noopTracer struct{}
+
+func (noopTracer) OnStart(*AGI)                                 {}
+func (noopTracer) OnHangup(*AGI)                                {}
+func (noopTracer) OnCommand([]string, *Response, time.Duration) {}
@@ -0,0 +1,272 @@
+package agi
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Server is a FastAGI server: it accepts TCP (or TLS, for AGIS)
+// connections and hands each one to Handler as an *AGI session, bounding
+// concurrency and supporting graceful shutdown, in the style of
+// net/http.Server.
+type Server struct {
+	// Addr is the TCP address to listen on, used by ListenAndServe and
+	// ListenAndServeTLS. Defaults to "localhost:4573" if empty.
+	Addr string
+
+	// Handler is invoked, in its own goroutine, with a new AGI session
+	// for every accepted connection.
+	Handler HandlerFunc
+
+	// MaxConcurrent bounds the number of sessions handled concurrently
+	// via a semaphore channel; once the limit is reached, Serve holds the
+	// next accepted connection until a slot frees and calls Accept no
+	// further, so later connections queue at the listener's backlog
+	// instead of forking unboundedly. Zero means unbounded.
+	MaxConcurrent int
+
+	// ReadTimeout, if non-zero, is the deadline applied to a connection
+	// as soon as it is accepted, bounding how long the initial AGI
+	// variable handshake may take.
+	ReadTimeout time.Duration
+
+	// IdleTimeout, if non-zero, is the deadline (re)applied to a
+	// connection between commands; once a session sits idle longer than
+	// this, reads and writes on its connection fail, so its next command
+	// returns an error.
+	IdleTimeout time.Duration
+
+	// TLSConfig is used by ListenAndServeTLS, merged with the loaded
+	// certificate, to serve AGIS (AGI-over-TLS) as offered by Asterisk's
+	// res_agi.
+	TLSConfig *tls.Config
+
+	// ErrorLog receives per-connection errors (a connection dropped
+	// because Handler is nil); a failed Accept is returned by Serve
+	// instead. If nil, the standard library's default logger is used.
+	ErrorLog *log.Logger
+
+	// BaseContext, if non-nil, is called once per listener passed to
+	// Serve to derive the context.Context made available to each of its
+	// sessions via AGI.Context(). Defaults to context.Background().
+	BaseContext func(net.Listener) context.Context
+
+	// Metrics, if non-nil, is attached to every session (via
+	// AGI.SetMetrics) and additionally receives ActiveSessions updates
+	// as connections are accepted and closed.
+	Metrics Metrics
+
+	// Tracer, if non-nil, is attached to every session via
+	// AGI.SetTracer, firing OnStart once each connection's initial
+	// variables have been read, before Handler runs.
+	Tracer Tracer
+
+	mu             sync.Mutex
+	listeners      map[net.Listener]struct{}
+	sem            chan struct{}
+	wg             sync.WaitGroup
+	closed         bool
+	activeSessions int64
+}
+
+// ListenAndServe listens on srv.Addr (defaulting to "localhost:4573") and
+// calls Serve to handle incoming connections.
+func (srv *Server) ListenAndServe() error {
+	addr := srv.Addr
+	if addr == "" {
+		addr = "localhost:4573"
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to bind server: %w", err)
+	}
+	return srv.Serve(l)
+}
+
+// ListenAndServeTLS listens on srv.Addr (defaulting to "localhost:4573")
+// and calls Serve to handle incoming AGIS connections, using certFile/
+// keyFile alongside any further options set on srv.TLSConfig.
+func (srv *Server) ListenAndServeTLS(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	config := srv.TLSConfig.Clone()
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config.Certificates = append(config.Certificates, cert)
+
+	addr := srv.Addr
+	if addr == "" {
+		addr = "localhost:4573"
+	}
+
+	l, err := tls.Listen("tcp", addr, config)
+	if err != nil {
+		return fmt.Errorf("failed to bind server: %w", err)
+	}
+	return srv.Serve(l)
+}
+
+// Serve accepts connections on l, handing each to Handler in its own
+// goroutine bounded by MaxConcurrent, until l is closed (by Shutdown or
+// otherwise) or Accept fails for any other reason.
+func (srv *Server) Serve(l net.Listener) error {
+	srv.mu.Lock()
+	if srv.closed {
+		srv.mu.Unlock()
+		_ = l.Close()
+		return ErrServerClosed
+	}
+	if srv.listeners == nil {
+		srv.listeners = make(map[net.Listener]struct{})
+	}
+	srv.listeners[l] = struct{}{}
+	if srv.MaxConcurrent > 0 && srv.sem == nil {
+		srv.sem = make(chan struct{}, srv.MaxConcurrent)
+	}
+	srv.mu.Unlock()
+
+	defer func() {
+		srv.mu.Lock()
+		delete(srv.listeners, l)
+		srv.mu.Unlock()
+	}()
+
+	baseCtx := context.Background()
+	if srv.BaseContext != nil {
+		baseCtx = srv.BaseContext(l)
+	}
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if srv.isShuttingDown() {
+				return ErrServerClosed
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		if srv.sem != nil {
+			srv.sem <- struct{}{}
+		}
+
+		// wg.Add must happen under mu, alongside Shutdown's closed check,
+		// so a connection accepted in the instant Shutdown runs can never
+		// race wg.Add(1) against Shutdown's wg.Wait(): whichever of the
+		// two observes the lock first determines whether this connection
+		// is handled or turned away, never "handled after Shutdown already
+		// finished waiting".
+		srv.mu.Lock()
+		if srv.closed {
+			srv.mu.Unlock()
+			if srv.sem != nil {
+				<-srv.sem
+			}
+			_ = conn.Close()
+			continue
+		}
+		srv.wg.Add(1)
+		srv.mu.Unlock()
+
+		go srv.handle(conn, baseCtx)
+	}
+}
+
+// handle runs a single accepted connection's AGI session to completion.
+func (srv *Server) handle(conn net.Conn, baseCtx context.Context) {
+	defer srv.wg.Done()
+	defer func() {
+		if srv.sem != nil {
+			<-srv.sem
+		}
+	}()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if srv.ReadTimeout > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(srv.ReadTimeout))
+	}
+
+	handler := srv.Handler
+	if handler == nil {
+		srv.logf("agi: Server.Handler is nil, dropping connection from %s", conn.RemoteAddr())
+		return
+	}
+
+	if srv.Metrics != nil {
+		n := atomic.AddInt64(&srv.activeSessions, 1)
+		srv.Metrics.ActiveSessions(int(n))
+		defer func() {
+			n := atomic.AddInt64(&srv.activeSessions, -1)
+			srv.Metrics.ActiveSessions(int(n))
+		}()
+	}
+
+	a := NewConnContext(baseCtx, conn)
+	if srv.IdleTimeout > 0 {
+		a.idleReset = func() {
+			_ = conn.SetDeadline(time.Now().Add(srv.IdleTimeout))
+		}
+		a.idleReset()
+	}
+	if srv.Metrics != nil {
+		a.SetMetrics(srv.Metrics)
+	}
+	if srv.Tracer != nil {
+		a.SetTracer(srv.Tracer)
+	}
+
+	handler(a)
+}
+
+func (srv *Server) logf(format string, args ...interface{}) {
+	if srv.ErrorLog != nil {
+		srv.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func (srv *Server) isShuttingDown() bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.closed
+}
+
+// Shutdown gracefully stops the server: it closes every listener passed
+// to Serve, so no further connections are accepted, then waits for
+// in-flight handlers to return. If ctx is done first, Shutdown returns
+// ctx.Err() without waiting any further; handlers already running are not
+// forcibly interrupted.
+func (srv *Server) Shutdown(ctx context.Context) error {
+	srv.mu.Lock()
+	srv.closed = true
+	for l := range srv.listeners {
+		_ = l.Close()
+	}
+	srv.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		srv.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,116 @@
+package agi
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startTestServer starts srv.Serve on an ephemeral localhost port in its
+// own goroutine and returns the listener's address. The caller is
+// responsible for calling srv.Shutdown.
+func startTestServer(t *testing.T, srv *Server) net.Addr {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	go func() {
+		_ = srv.Serve(l)
+	}()
+
+	return l.Addr()
+}
+
+// dialSession dials addr and writes the blank line that terminates the AGI
+// initial-variable handshake, as Asterisk would, so the accepted
+// connection's Handler actually starts running rather than blocking in
+// newAGI's variable scan.
+func dialSession(t *testing.T, addr net.Addr) net.Conn {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	if _, err := conn.Write([]byte("\n")); err != nil {
+		t.Fatalf("write handshake: %v", err)
+	}
+	return conn
+}
+
+// TestServer_MaxConcurrentBoundsConcurrency confirms that with
+// MaxConcurrent set, Serve never lets more than that many Handler
+// invocations run at once, even when more connections are dialed.
+func TestServer_MaxConcurrentBoundsConcurrency(t *testing.T) {
+	release := make(chan struct{})
+	var current int64
+
+	srv := &Server{
+		MaxConcurrent: 1,
+		Handler: func(a *AGI) {
+			atomic.AddInt64(&current, 1)
+			<-release
+			atomic.AddInt64(&current, -1)
+		},
+	}
+	defer func() { _ = srv.Shutdown(context.Background()) }()
+
+	addr := startTestServer(t, srv)
+
+	conn1 := dialSession(t, addr)
+	defer conn1.Close()
+
+	// Give the first session time to register as running before dialing
+	// a second one, so the assertion below observes steady state.
+	time.Sleep(50 * time.Millisecond)
+
+	conn2 := dialSession(t, addr)
+	defer conn2.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	if got := atomic.LoadInt64(&current); got != 1 {
+		t.Fatalf("current concurrent sessions = %d, want 1 (MaxConcurrent should hold the second connection back)", got)
+	}
+
+	close(release)
+}
+
+// TestServer_ShutdownWaitsForActiveSessions confirms Shutdown blocks
+// until an in-flight handler returns, and returns ctx.Err() instead of
+// waiting forever if ctx is done first.
+func TestServer_ShutdownWaitsForActiveSessions(t *testing.T) {
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	srv := &Server{
+		Handler: func(a *AGI) {
+			<-release
+			close(done)
+		},
+	}
+
+	addr := startTestServer(t, srv)
+
+	conn := dialSession(t, addr)
+	defer conn.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	shortCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := srv.Shutdown(shortCtx); err != shortCtx.Err() {
+		t.Fatalf("Shutdown with an expiring ctx returned %v, want ctx.Err()", err)
+	}
+
+	close(release)
+	<-done
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown after handler completed: unexpected error: %v", err)
+	}
+}